@@ -6,6 +6,7 @@ package main
 
 import (
 	"bytes"
+	"encoding/base64"
 	"flag"
 	"fmt"
 	"go/format"
@@ -27,6 +28,26 @@ const (
 	TYPE_GEOMETRY = "geometry"
 )
 
+// shaderUtilImport is the import path of the runtime support package used
+// by the generated Loader to watch and re-read shader sources from disk.
+const shaderUtilImport = "github.com/vshashi01/engine/renderer/shaders/shaderutil"
+
+// backendExt maps the file extension of a shader or variant file to the
+// backend it targets. A file extension not present here is ignored.
+var backendExt = map[string]string{
+	".glsl":  "glsl",
+	".spv":   "spirv",
+	".metal": "metal",
+	".hlsl":  "hlsl",
+}
+
+// binaryBackends holds the backends whose sources are opaque binary blobs
+// (such as precompiled SPIR-V) rather than text, and so must be embedded
+// as []byte instead of as a Go string literal.
+var binaryBackends = map[string]bool{
+	"spirv": true,
+}
+
 //
 // Go template to generate the output file with the shaders' sources and
 // maps describing the include and shader names and programs shaders.
@@ -44,34 +65,364 @@ const include_{{.Name}}_source = ` + "`{{.Source}}`" + `
 const {{.Name}}_source = ` + "`{{.Source}}`" + `
 {{end}}
 
-// Maps include name with its source code
-var includeMap = map[string]string {
-{{range .Includes}}
-	"{{- .Name}}": include_{{.Name}}_source, {{end}}
+// embeddedIncludeMap returns the include name to source code map embedded
+// at generate time. Loader.LoadFromEmbedded restores includeMap to this.
+func embeddedIncludeMap() map[string]string {
+	return map[string]string {
+	{{range .Includes}}
+		"{{- .Name}}": include_{{.Name}}_source, {{end}}
+	}
 }
 
+// embeddedShaderMap returns the shader name to source code map embedded
+// at generate time. Loader.LoadFromEmbedded restores shaderMap to this.
+func embeddedShaderMap() map[string]string {
+	return map[string]string {
+	{{range .Shaders}}
+		"{{- .Name}}": {{.Name}}_source, {{end}}
+	}
+}
+
+// Maps include name with its source code
+var includeMap = embeddedIncludeMap()
+
 // Maps shader name with its source code
-var shaderMap = map[string]string {
-{{range .Shaders}}
-	"{{- .Name}}": {{.Name}}_source, {{end}}
+var shaderMap = embeddedShaderMap()
+
+// Maps program name with Proginfo struct with shaders names. Programs
+// whose shaders declare '#pragma g3n permute' directives additionally get
+// one entry per permutation, named "<program>__KEY=VALUE...".
+var programMap = map[string]ProgramInfo{
+{{ range $progName, $progInfo := .Programs }}
+	"{{$progName}}": { "{{$progInfo.Vertex}}","{{$progInfo.Fragment}}","{{$progInfo.Geometry}}" }, {{end}}
+{{ range $progName, $progInfo := .PermPrograms }}
+	"{{$progName}}": { "{{$progInfo.Vertex}}","{{$progInfo.Fragment}}","{{$progInfo.Geometry}}" }, {{end}}
+}
+`
+
+//
+// Go template to generate the Loader companion type that lets the
+// package's shaders be reloaded from the original source directory at
+// runtime instead of from the sources embedded by TEMPLATE.
+//
+const LOADER_TEMPLATE = `// File generated by G3NSHADERS. Do not edit.
+// To regenerate this file install 'g3nshaders' and execute:
+// 'go generate' in this folder.
+package {{.Pkg}}
+
+import (
+	"context"
+	"sync"
+
+	"{{.ShaderUtilImport}}"
+)
+
+// srcDir is the shader source directory used to generate this package,
+// recorded here so Loader has a sensible default for development builds.
+const srcDir = "{{.SrcDir}}"
+
+// Loader lets a running program reload this package's shaders from disk,
+// bypassing the sources embedded by g3nshaders at generate time. Programs
+// are registered with RegisterProgram and relinked by Watch whenever
+// their shaders change on disk. mu guards dir and programs, since Watch reads
+// them from its own goroutine while the caller may call RegisterProgram
+// or LoadFromDir concurrently from another.
+type Loader struct {
+	mu       sync.Mutex
+	dir      string
+	programs map[string]func() error
+}
+
+// NewLoader creates a Loader with no registered programs. Call
+// LoadFromEmbedded or LoadFromDir before Watch.
+func NewLoader() *Loader {
+	return &Loader{programs: make(map[string]func() error)}
+}
+
+// LoadFromEmbedded resets includeMap and shaderMap to the sources that
+// were embedded by g3nshaders at generate time. This is the behavior
+// release builds always use.
+func (l *Loader) LoadFromEmbedded() error {
+	includeMap = embeddedIncludeMap()
+	shaderMap = embeddedShaderMap()
+	return nil
+}
+
+// LoadFromDir re-reads includeMap and shaderMap from the ".glsl" sources
+// in dir, resolving #include directives and expanding '#pragma g3n
+// permute' directives the same way g3nshaders does at generate time, so
+// permutation-named programs keep resolving after a reload. Use this
+// during development to pick up edits without a go generate + rebuild
+// cycle.
+func (l *Loader) LoadFromDir(path string) error {
+	dl := shaderutil.NewDirLoader(path)
+	includes, shaders, err := dl.Load()
+	if err != nil {
+		return err
+	}
+	includeMap = includes
+	shaderMap = shaders
+	l.mu.Lock()
+	l.dir = path
+	l.mu.Unlock()
+	return nil
+}
+
+// RegisterProgram associates a program name with the function that
+// rebuilds (relinks) it, so Watch can call it back when that program's
+// shaders change on disk.
+func (l *Loader) RegisterProgram(name string, relink func() error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.programs[name] = relink
+}
+
+// Watch watches l's source directory, set by a prior call to LoadFromDir,
+// for shader changes. On every change it re-reads and re-resolves
+// includeMap/shaderMap from dir before relinking every registered program
+// affected by the change and reporting on every affected program,
+// registered or not, so relink sees the changed
+// source instead of recompiling what LoadFromDir last loaded. The
+// returned channel is closed when ctx is done.
+func (l *Loader) Watch(ctx context.Context) <-chan shaderutil.ReloadEvent {
+	l.mu.Lock()
+	dir := l.dir
+	l.mu.Unlock()
+	if dir == "" {
+		dir = srcDir
+	}
+	dl := shaderutil.NewDirLoader(dir)
+	out := make(chan shaderutil.ReloadEvent)
+	events, err := dl.Watch(ctx, l.programsUsing)
+	if err != nil {
+		go func() {
+			out <- shaderutil.ReloadEvent{Err: err}
+			close(out)
+		}()
+		return out
+	}
+	go func() {
+		defer close(out)
+		for ev := range events {
+			if ev.Err != nil {
+				out <- ev
+				continue
+			}
+			includes, shaders, err := dl.Load()
+			if err != nil {
+				ev.Err = err
+				out <- ev
+				continue
+			}
+			includeMap = includes
+			shaderMap = shaders
+			l.mu.Lock()
+			relink, ok := l.programs[ev.Program]
+			l.mu.Unlock()
+			if ok {
+				if err := relink(); err != nil {
+					ev.Err = err
+				}
+			}
+			out <- ev
+		}
+	}()
+	return out
+}
+
+// programsUsing returns the names of every program in programMap whose
+// vertex, fragment or geometry shader is named name or, when name is an
+// include, of every program in programMap: which programs use a given
+// include is not tracked.
+func (l *Loader) programsUsing(name string) []string {
+	var names []string
+	for prog, info := range programMap {
+		if info.Vertex == name || info.Fragment == name || info.Geometry == name {
+			names = append(names, prog)
+			continue
+		}
+		if _, ok := includeMap[name]; ok {
+			names = append(names, prog)
+		}
+	}
+	return names
+}
+`
+
+//
+// Go template to generate the ShaderVariant type and the variantMap,
+// which together let a program be built from a non-GLSL or precompiled
+// backend (SPIR-V, Metal, HLSL) instead of only the GLSL sources emitted
+// into shaderMap by TEMPLATE.
+//
+const VARIANT_TEMPLATE = `// File generated by G3NSHADERS. Do not edit.
+// To regenerate this file install 'g3nshaders' and execute:
+// 'go generate' in this folder.
+package {{.Pkg}}
+
+import "encoding/base64"
+
+// ShaderVariant describes one backend-specific build of a single shader
+// stage of a program.
+type ShaderVariant struct {
+	Prog    string // program name, e.g. "standard"
+	Stage   string // vertex, fragment or geometry
+	Backend string // glsl, spirv, metal, hlsl
+	Source  string // source text; empty when Binary is set
+	Binary  []byte // binary blob (e.g. compiled SPIR-V); nil when Source is set
 }
 
-// Maps program name with Proginfo struct with shaders names
+{{range .Variants}}{{if .IsBinary}}
+var variantData_{{.Ident}} = mustDecodeBase64("{{.Base64}}")
+{{else}}
+const variantSource_{{.Ident}} = ` + "`{{.Source}}`" + `
+{{end}}{{end}}
+
+// mustDecodeBase64 decodes a base64-encoded binary shader blob embedded
+// by g3nshaders. It panics on error, since the input is generated and
+// any failure indicates a bug in g3nshaders itself.
+func mustDecodeBase64(s string) []byte {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// variantMap maps a program name to every backend variant of its vertex,
+// fragment and geometry shaders.
+var variantMap = map[string][]ShaderVariant{
+{{range $prog, $variants := .ProgramVariants}}
+	"{{$prog}}": { {{range $variants}}{ "{{.Prog}}", "{{.Stage}}", "{{.Backend}}", {{if .IsBinary}}"", variantData_{{.Ident}}{{else}}variantSource_{{.Ident}}, nil{{end}} },
+	{{end}} },
+{{end}}
+}
+`
+
+//
+// Go template to generate sources_debug.go: every shader and permutation
+// with its includes already resolved and #line directives pointing back
+// to the file and line the text came from, so a GLSL compiler error can
+// be mapped back to the original .glsl source instead of to the
+// concatenated text g3nshaders produced. Only built with the g3ndebug
+// build tag, since it duplicates shaderMap's sources.
+//
+const DEBUG_TEMPLATE = `// File generated by G3NSHADERS. Do not edit.
+// To regenerate this file install 'g3nshaders' and execute:
+// 'go generate' in this folder.
+
+//go:build g3ndebug
+// +build g3ndebug
+
+package {{.Pkg}}
+
+// debugSourceMap maps every shader and permutation name to its fully
+// expanded source (includes resolved, '#line' directives intact).
+var debugSourceMap = map[string]string{
+{{range .DebugShaders}}
+	"{{.Name}}": ` + "`{{.Source}}`" + `,
+{{end}}
+}
+`
+
+//
+// Go template, used with -mode embed instead of TEMPLATE, to generate an
+// output file that reads its shader sources from an "assets" tree laid
+// out alongside it via go:embed instead of inlining them as Go string
+// literals. It exposes the same includeMap, shaderMap and programMap as
+// TEMPLATE.
+//
+const EMBED_TEMPLATE = `// File generated by G3NSHADERS. Do not edit.
+// To regenerate this file install 'g3nshaders' and execute:
+// 'go generate' in this folder.
+package {{.Pkg}}
+
+import (
+	"embed"
+	"path"
+	"strings"
+)
+
+//go:embed assets
+var assetsFS embed.FS
+
+// embeddedIncludeMap reads every include source out of the embedded
+// assets tree. Loader.LoadFromEmbedded restores includeMap to this.
+func embeddedIncludeMap() map[string]string {
+	return mustReadAssetsDir("assets/include")
+}
+
+// embeddedShaderMap reads every shader source out of the embedded assets
+// tree. Loader.LoadFromEmbedded restores shaderMap to this.
+func embeddedShaderMap() map[string]string {
+	return mustReadAssetsDir("assets")
+}
+
+// mustReadAssetsDir reads every ".glsl" file directly inside dir of the
+// embedded assets tree into a name -> source map keyed by file name
+// without extension. It does not recurse, so "assets/include"'s own
+// files are not picked up when reading "assets" itself.
+func mustReadAssetsDir(dir string) map[string]string {
+	entries, err := assetsFS.ReadDir(dir)
+	if err != nil {
+		panic(err)
+	}
+	m := make(map[string]string, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".glsl") {
+			continue
+		}
+		data, err := assetsFS.ReadFile(path.Join(dir, e.Name()))
+		if err != nil {
+			panic(err)
+		}
+		m[strings.TrimSuffix(e.Name(), ".glsl")] = string(data)
+	}
+	return m
+}
+
+// Maps include name with its source code
+var includeMap = embeddedIncludeMap()
+
+// Maps shader name with its source code
+var shaderMap = embeddedShaderMap()
+
+// Maps program name with Proginfo struct with shaders names. Programs
+// whose shaders declare '#pragma g3n permute' directives additionally get
+// one entry per permutation, named "<program>__KEY=VALUE...".
 var programMap = map[string]ProgramInfo{
 {{ range $progName, $progInfo := .Programs }}
 	"{{$progName}}": { "{{$progInfo.Vertex}}","{{$progInfo.Fragment}}","{{$progInfo.Geometry}}" }, {{end}}
+{{ range $progName, $progInfo := .PermPrograms }}
+	"{{$progName}}": { "{{$progInfo.Vertex}}","{{$progInfo.Fragment}}","{{$progInfo.Geometry}}" }, {{end}}
 }
 `
 
 // Command line options
 var (
-	oVersion = flag.Bool("version", false, "Show version and exits")
-	oInp     = flag.String("in", ".", "Input directory")
-	oOut     = flag.String("out", "sources.go", "Go output file")
-	oPackage = flag.String("pkg", "shaders", "Package name")
-	oVerbose = flag.Bool("v", false, "Show files being processed")
+	oVersion    = flag.Bool("version", false, "Show version and exits")
+	oInp        = flag.String("in", ".", "Input directory")
+	oOut        = flag.String("out", "sources.go", "Go output file")
+	oLoaderOut  = flag.String("loaderout", "loader.go", "Go loader output file")
+	oVariantOut = flag.String("variantout", "variants.go", "Go shader variants output file")
+	oDebugOut   = flag.String("debugout", "sources_debug.go", "Go debug sources output file")
+	oMode       = flag.String("mode", "inline", "Generation mode for the output file: inline (Go string literals) or embed (go:embed against a generated assets tree)")
+	oPackage    = flag.String("pkg", "shaders", "Package name")
+	oBackends   = flag.String("backends", "", "Comma separated list of backends to include (default: all)")
+	oValidate   = flag.Bool("validate", false, "Validate shaders (via glslangValidator, if available) before writing the output file")
+	oVerbose    = flag.Bool("v", false, "Show files being processed")
 )
 
+// Valid -mode values
+const (
+	MODE_INLINE = "inline"
+	MODE_EMBED  = "embed"
+)
+
+// backendFilter, when non-nil, restricts processFile to the backends it
+// contains; built from -backends in main.
+var backendFilter map[string]bool
+
 // Valid shader types
 var shaderTypes = map[string]bool{
 	TYPE_VERTEX:   true,
@@ -94,12 +445,45 @@ type progInfo struct {
 	Geometry string // geometry shader name
 }
 
+// variantInfo describes one backend-specific build of a single shader
+// stage of a program, keyed by (Prog, Stage, Backend).
+type variantInfo struct {
+	Prog    string // program name, e.g. "standard"
+	Stage   string // vertex, fragment or geometry
+	Backend string // glsl, spirv, metal, hlsl
+	Source  string // source text; empty when Binary is set
+	Binary  []byte // binary blob (e.g. compiled SPIR-V); nil when Source is set
+}
+
+// Ident returns a name derived from v's key suitable for use as (part of)
+// a Go identifier in the generated variants.go.
+func (v variantInfo) Ident() string {
+	return v.Prog + "_" + v.Stage + "_" + v.Backend
+}
+
+// IsBinary reports whether v's backend embeds a binary blob.
+func (v variantInfo) IsBinary() bool {
+	return binaryBackends[v.Backend]
+}
+
+// Base64 returns v's binary blob, base64 encoded, for embedding as a Go
+// string literal in the generated source.
+func (v variantInfo) Base64() string {
+	return base64.StdEncoding.EncodeToString(v.Binary)
+}
+
 // templInfo contains all information needed for the template expansion
 type templInfo struct {
-	Pkg      string
-	Includes []fileInfo
-	Shaders  []fileInfo
-	Programs map[string]progInfo
+	Pkg              string
+	Includes         []fileInfo
+	Shaders          []fileInfo
+	Programs         map[string]progInfo
+	Variants         []variantInfo
+	ProgramVariants  map[string][]variantInfo
+	PermPrograms     map[string]progInfo // programs instantiated from permutations, see preprocessShaders
+	DebugShaders     []expandedShader    // every shader/permutation with includes resolved, for sources_debug.go
+	SrcDir           string              // original shader source directory, for Loader's default
+	ShaderUtilImport string              // import path of the Loader's runtime support package
 }
 
 var templData templInfo
@@ -116,16 +500,63 @@ func main() {
 		return
 	}
 
+	// Build the backend filter from -backends, if given
+	if *oBackends != "" {
+		backendFilter = make(map[string]bool)
+		for _, b := range strings.Split(*oBackends, ",") {
+			backendFilter[strings.TrimSpace(b)] = true
+		}
+	}
+
 	// Initialize template data
 	templData.Pkg = *oPackage
 	templData.Programs = make(map[string]progInfo)
+	templData.ProgramVariants = make(map[string][]variantInfo)
+	templData.SrcDir = *oInp
+	templData.ShaderUtilImport = shaderUtilImport
 
 	// Process the current directory and its subdirectories recursively
 	// appending information into templData
 	processDir(*oInp, false)
 
-	// Generates output file from TEMPLATE
-	generate(*oOut)
+	// Resolve #include directives and expand #pragma g3n permute
+	// directives now that every include and shader has been read
+	preprocessShaders()
+
+	// If requested, validate every program's shaders before generating
+	// anything, so a broken shader fails go generate instead of the next
+	// GL link at runtime
+	if *oValidate {
+		if diags := validate(); len(diags) > 0 {
+			for _, d := range diags {
+				fmt.Fprintln(os.Stderr, d.String())
+			}
+			fmt.Fprintf(os.Stderr, "g3nshaders: %d validation error(s)\n", len(diags))
+			os.Exit(1)
+		}
+	}
+
+	// Generates the main output file, either inlining sources as Go string
+	// literals (TEMPLATE) or laying out an assets tree read via go:embed
+	// (EMBED_TEMPLATE), depending on -mode
+	switch *oMode {
+	case MODE_INLINE:
+		generate(TEMPLATE, *oOut)
+	case MODE_EMBED:
+		writeAssets(filepath.Join(filepath.Dir(*oOut), "assets"))
+		generate(EMBED_TEMPLATE, *oOut)
+	default:
+		panic(fmt.Errorf("g3nshaders: invalid -mode %q (want %q or %q)", *oMode, MODE_INLINE, MODE_EMBED))
+	}
+
+	// Generates loader output file from LOADER_TEMPLATE
+	generate(LOADER_TEMPLATE, *oLoaderOut)
+
+	// Generates shader variants output file from VARIANT_TEMPLATE
+	generate(VARIANT_TEMPLATE, *oVariantOut)
+
+	// Generates debug sources output file from DEBUG_TEMPLATE
+	generate(DEBUG_TEMPLATE, *oDebugOut)
 }
 
 // processDir processes recursively all shaders files in the specified directory
@@ -164,13 +595,34 @@ func processDir(dir string, include bool) {
 // it a shader
 func processFile(file string, include bool) {
 
-	// Ignore file if it has not the shader extension
+	// Include files are always plain GLSL text; shader program files may
+	// additionally use the extended <name>_<stage>.<backend> convention,
+	// where the extension selects the target backend.
 	fext := filepath.Ext(file)
-	if fext != SHADEREXT {
-		if *oVerbose {
-			fmt.Printf("Ignored file (not shader): %s\n", file)
+	var backend string
+	if include {
+		if fext != SHADEREXT {
+			if *oVerbose {
+				fmt.Printf("Ignored file (not shader): %s\n", file)
+			}
+			return
+		}
+		backend = backendExt[SHADEREXT]
+	} else {
+		var ok bool
+		backend, ok = backendExt[fext]
+		if !ok {
+			if *oVerbose {
+				fmt.Printf("Ignored file (not shader): %s\n", file)
+			}
+			return
+		}
+		if backendFilter != nil && !backendFilter[backend] {
+			if *oVerbose {
+				fmt.Printf("Ignored file (backend %q excluded): %s\n", backend, file)
+			}
+			return
 		}
-		return
 	}
 
 	// Get the file base name and its name with the extension
@@ -179,18 +631,19 @@ func processFile(file string, include bool) {
 
 	// If not in include directory, the file must be a shader program
 	// which name must have the format: <name>_<shader_type>
+	var sname, stype string
 	if !include {
 		parts := strings.Split(string(fname), "_")
 		if len(parts) < 2 {
 			fmt.Printf("Ignored file (INVALID NAME): %s\n", file)
 			return
 		}
-		stype := parts[len(parts)-1]
+		stype = parts[len(parts)-1]
 		if !shaderTypes[stype] {
 			fmt.Printf("Ignored file (INVALID SHADER TYPE): %s\n", file)
 			return
 		}
-		sname := strings.Join(parts[:len(parts)-1], "_")
+		sname = strings.Join(parts[:len(parts)-1], "_")
 		pinfo, ok := templData.Programs[sname]
 		if !ok {
 			templData.Programs[sname] = pinfo
@@ -217,6 +670,30 @@ func processFile(file string, include bool) {
 		panic(err)
 	}
 
+	// Record this shader program file as a variant of its program, keyed
+	// by (program, stage, backend), so downstream code can pick a backend
+	// at runtime instead of only the one embedded into shaderMap.
+	if !include {
+		variant := variantInfo{Prog: sname, Stage: stype, Backend: backend}
+		if binaryBackends[backend] {
+			variant.Binary = data
+		} else {
+			variant.Source = string(data)
+		}
+		templData.Variants = append(templData.Variants, variant)
+		templData.ProgramVariants[sname] = append(templData.ProgramVariants[sname], variant)
+	}
+
+	// Only the default GLSL text shaders are embedded into the legacy
+	// shaderMap/includeMap; other backends are only available through
+	// variantMap.
+	if !include && backend != backendExt[SHADEREXT] {
+		if *oVerbose {
+			fmt.Printf("%s (%v bytes, variant only)\n", file, len(data))
+		}
+		return
+	}
+
 	// Appends entry in Includes or Shaders
 	if include {
 		templData.Includes = append(templData.Includes, fileInfo{
@@ -234,12 +711,45 @@ func processFile(file string, include bool) {
 	}
 }
 
-// generate generates output go file with shaders sources from TEMPLATE
-func generate(file string) {
+// writeAssets lays out templData's includes and shaders as individual
+// ".glsl" files under dir, with includes in a further "include"
+// subdirectory, for -mode embed's EMBED_TEMPLATE to pick up with
+// go:embed. It writes the post-preprocessing sources, so the embedded
+// tree matches what TEMPLATE would have inlined for the same input.
+func writeAssets(dir string) {
+
+	// Remove any previous assets tree first, so a file dropped from the
+	// source tree since the last generate (e.g. a renamed or deleted
+	// shader) doesn't linger here and keep being picked up by go:embed.
+	if err := os.RemoveAll(dir); err != nil {
+		panic(err)
+	}
+
+	includeDir := filepath.Join(dir, DIR_INCLUDE)
+	if err := os.MkdirAll(includeDir, 0755); err != nil {
+		panic(err)
+	}
+	for _, inc := range templData.Includes {
+		path := filepath.Join(includeDir, inc.Name+SHADEREXT)
+		if err := ioutil.WriteFile(path, []byte(inc.Source), 0644); err != nil {
+			panic(err)
+		}
+	}
+	for _, sh := range templData.Shaders {
+		path := filepath.Join(dir, sh.Name+SHADEREXT)
+		if err := ioutil.WriteFile(path, []byte(sh.Source), 0644); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// generate generates the output file from the expansion of templ with
+// the current templData, writing the result to file.
+func generate(templ string, file string) {
 
 	// Parses the template
 	tmpl := template.New("tmpl")
-	tmpl, err := tmpl.Parse(TEMPLATE)
+	tmpl, err := tmpl.Parse(templ)
 	if err != nil {
 		panic(err)
 	}
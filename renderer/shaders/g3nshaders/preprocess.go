@@ -0,0 +1,173 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/vshashi01/engine/renderer/shaders/shaderutil"
+)
+
+// expandedShader is one fully preprocessed (includes resolved, permute
+// pragmas expanded) build of a shader source, with '#line' directives
+// intact, for sources_debug.go.
+type expandedShader struct {
+	Name   string // concrete name, e.g. "standard_vertex" or "standard_vertex__FOG=0__SKIN=1"
+	Base   string // name of the shader file this was expanded from
+	Source string // fully expanded GLSL source, with #line directives
+}
+
+// preprocessShaders resolves #include directives and expands #pragma g3n
+// permute directives for every shader in templData.Shaders. It rewrites
+// each shader's Source to its includes-resolved, pragma-stripped form
+// (the text that ships in the generated shaderMap, which must not contain
+// '#line' directives: GLSL's '#line' takes a decimal source-string-number
+// as its second parameter, not a filename, and most drivers reject the
+// quoted-filename form used for debugging). It separately collects a
+// '#line'-annotated expansion of every shader (including permutations)
+// into templData.DebugShaders, for sources_debug.go, and builds
+// templData.PermPrograms so programMap can reference concrete permutation
+// names.
+func preprocessShaders() {
+
+	includes := make(map[string]string, len(templData.Includes))
+	for _, inc := range templData.Includes {
+		includes[inc.Name] = inc.Source
+	}
+
+	// permsByShader maps a shader name to the permute directives found in
+	// its source, in the order they were declared. debugSource holds the
+	// '#line'-annotated resolution of each shader, used only to build
+	// templData.DebugShaders.
+	permsByShader := make(map[string][]shaderutil.PermuteDirective)
+	debugSource := make(map[string]string, len(templData.Shaders))
+
+	for i, sh := range templData.Shaders {
+		ref := shaderFileRef(sh.Name)
+		resolved, err := shaderutil.ResolveIncludes(ref, sh.Source, includes, nil, false)
+		if err != nil {
+			panic(fmt.Errorf("g3nshaders: %s: %v", sh.Name, err))
+		}
+		debugResolved, err := shaderutil.ResolveIncludes(ref, sh.Source, includes, nil, true)
+		if err != nil {
+			panic(fmt.Errorf("g3nshaders: %s: %v", sh.Name, err))
+		}
+		perms, stripped := shaderutil.ExtractPermutations(resolved)
+		_, debugStripped := shaderutil.ExtractPermutations(debugResolved)
+		templData.Shaders[i].Source = stripped
+		debugSource[sh.Name] = debugStripped
+		if len(perms) > 0 {
+			permsByShader[sh.Name] = perms
+		}
+		templData.DebugShaders = append(templData.DebugShaders, expandedShader{
+			Name:   sh.Name,
+			Base:   sh.Name,
+			Source: debugStripped,
+		})
+	}
+
+	// Expand every shader with permute directives into one concrete
+	// shader per combination of values, and register each as both a
+	// shaderMap entry and a debug source.
+	expanded := make(map[string][]expandedShader) // base shader name -> its permutations
+	for _, sh := range templData.Shaders {
+		perms, ok := permsByShader[sh.Name]
+		if !ok {
+			continue
+		}
+		for _, combo := range shaderutil.PermuteCombinations(perms) {
+			suffix := shaderutil.ComboSuffix(combo)
+			name := sh.Name + suffix
+			defines := shaderutil.DefineLines(combo)
+			templData.Shaders = append(templData.Shaders, fileInfo{Name: name, Source: defines + sh.Source})
+			templData.DebugShaders = append(templData.DebugShaders, expandedShader{
+				Name:   name,
+				Base:   sh.Name,
+				Source: defines + fmt.Sprintf("#line 1 %q\n", shaderFileRef(sh.Name)) + debugSource[sh.Name],
+			})
+			expanded[sh.Name] = append(expanded[sh.Name], expandedShader{Name: name, Base: sh.Name})
+		}
+	}
+	if len(expanded) == 0 {
+		return
+	}
+
+	// Build one concrete program per combination of permutations used by
+	// any of a program's stages, so programMap can reference permutation
+	// names like "standard_vertex__FOG=0__SKIN=1" directly.
+	templData.PermPrograms = make(map[string]progInfo)
+	for progName, info := range templData.Programs {
+		stages := map[string]string{TYPE_VERTEX: info.Vertex, TYPE_FRAGMENT: info.Fragment, TYPE_GEOMETRY: info.Geometry}
+		if !anyStagePermuted(stages, expanded) {
+			continue
+		}
+		for _, suffix := range permutationSuffixes(stages, expanded) {
+			perm := progInfo{
+				Vertex:   stageName(info.Vertex, suffix, expanded),
+				Fragment: stageName(info.Fragment, suffix, expanded),
+				Geometry: stageName(info.Geometry, suffix, expanded),
+			}
+			templData.PermPrograms[progName+suffix] = perm
+		}
+	}
+}
+
+// shaderFileRef formats a shader name as it should appear in #line
+// directives and error messages.
+func shaderFileRef(name string) string {
+	return name + SHADEREXT
+}
+
+// anyStagePermuted reports whether any of a program's stage shader names
+// has registered permutations.
+func anyStagePermuted(stages map[string]string, expanded map[string][]expandedShader) bool {
+	for _, name := range stages {
+		if name == "" {
+			continue
+		}
+		if _, ok := expanded[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// permutationSuffixes returns, for a program whose stages are named in
+// stages, the name suffix of every permutation that program must be
+// instantiated as: the union, across its permuted stages, of each
+// stage's own permutation suffixes.
+func permutationSuffixes(stages map[string]string, expanded map[string][]expandedShader) []string {
+	seen := make(map[string]bool)
+	var suffixes []string
+	for _, name := range stages {
+		for _, e := range expanded[name] {
+			suffix := strings.TrimPrefix(e.Name, e.Base)
+			if seen[suffix] {
+				continue
+			}
+			seen[suffix] = true
+			suffixes = append(suffixes, suffix)
+		}
+	}
+	sort.Strings(suffixes)
+	return suffixes
+}
+
+// stageName returns the concrete shader name a program stage should use
+// for the permutation named by suffix: the stage's own permutation if it
+// has one by that suffix, otherwise its unpermuted base name.
+func stageName(base, suffix string, expanded map[string][]expandedShader) string {
+	if base == "" {
+		return ""
+	}
+	for _, e := range expanded[base] {
+		if strings.TrimPrefix(e.Name, e.Base) == suffix {
+			return e.Name
+		}
+	}
+	return base
+}
@@ -0,0 +1,317 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// glslangStageExt maps a shader stage to the file extension
+// glslangValidator uses to infer its stage.
+var glslangStageExt = map[string]string{
+	TYPE_VERTEX:   ".vert",
+	TYPE_FRAGMENT: ".frag",
+	TYPE_GEOMETRY: ".geom",
+}
+
+// reGlslangDiag matches one diagnostic line of glslangValidator's output,
+// e.g. "ERROR: 0:12: 'foo' : undeclared identifier".
+var reGlslangDiag = regexp.MustCompile(`^(ERROR|WARNING): \d+:(\d+): (.*)$`)
+
+// diagnostic is one validation failure, reported as file:line: message.
+type diagnostic struct {
+	File string
+	Line int
+	Msg  string
+}
+
+func (d diagnostic) String() string {
+	if d.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s", d.File, d.Line, d.Msg)
+	}
+	return fmt.Sprintf("%s: %s", d.File, d.Msg)
+}
+
+// validate checks every program built from templData (including
+// permutations) and returns every diagnostic found: missing required
+// stages, shader syntax errors, and vertex/fragment interface mismatches.
+// It must run after preprocessShaders, since it validates resolved
+// sources, not raw per-file ones.
+func validate() []diagnostic {
+
+	shaderByName := make(map[string]fileInfo, len(templData.Shaders))
+	for _, sh := range templData.Shaders {
+		shaderByName[sh.Name] = sh
+	}
+
+	hasGlslang := glslangAvailable()
+	if !hasGlslang {
+		fmt.Fprintln(os.Stderr, "g3nshaders: glslangValidator not found in PATH, falling back to built-in checks")
+	}
+
+	programs := make(map[string]progInfo, len(templData.Programs)+len(templData.PermPrograms))
+	for name, info := range templData.Programs {
+		programs[name] = info
+	}
+	for name, info := range templData.PermPrograms {
+		programs[name] = info
+	}
+
+	var diags []diagnostic
+	checkedStages := make(map[string]bool)
+	for prog, info := range programs {
+		diags = append(diags, validateProgram(prog, info, shaderByName, hasGlslang, checkedStages)...)
+	}
+	sort.Slice(diags, func(i, j int) bool {
+		if diags[i].File != diags[j].File {
+			return diags[i].File < diags[j].File
+		}
+		return diags[i].Line < diags[j].Line
+	})
+	return diags
+}
+
+// validateProgram checks a single program: that it has a vertex and
+// fragment stage, that each of its stages compiles, and that the vertex
+// shader's outputs line up with the fragment shader's inputs. A geometry
+// stage is compiled but its interfaces are not checked: the vertex
+// outputs are compared with the fragment inputs directly, also when a
+// geometry stage is present. checkedStages deduplicates stage compilation
+// across programs that share a shader.
+func validateProgram(prog string, info progInfo, shaders map[string]fileInfo, hasGlslang bool, checkedStages map[string]bool) []diagnostic {
+
+	var diags []diagnostic
+	if info.Vertex == "" || info.Fragment == "" {
+		diags = append(diags, diagnostic{
+			File: prog,
+			Msg:  "program must have at least a vertex and a fragment shader",
+		})
+	}
+
+	for _, stage := range []struct{ typ, name string }{
+		{TYPE_VERTEX, info.Vertex},
+		{TYPE_FRAGMENT, info.Fragment},
+		{TYPE_GEOMETRY, info.Geometry},
+	} {
+		if stage.name == "" || checkedStages[stage.name] {
+			continue
+		}
+		checkedStages[stage.name] = true
+		sh, ok := shaders[stage.name]
+		if !ok {
+			if hasNonGLSLVariant(prog, stage.typ) {
+				// This stage was never built as GLSL text, whether
+				// because -backends excluded it or because the program
+				// only ships precompiled/non-GLSL backends for it: there
+				// is nothing here for the built-in checks or
+				// glslangValidator to validate.
+				continue
+			}
+			diags = append(diags, diagnostic{File: stage.name, Msg: "shader not found"})
+			continue
+		}
+		if hasGlslang {
+			diags = append(diags, runGlslangValidator(sh.Name, stage.typ, sh.Source)...)
+		} else {
+			diags = append(diags, checkBalanced(sh.Name, sh.Source)...)
+		}
+	}
+
+	if info.Vertex != "" && info.Fragment != "" {
+		diags = append(diags, checkInterface(info.Vertex, shaders[info.Vertex].Source, info.Fragment, shaders[info.Fragment].Source)...)
+	}
+	return diags
+}
+
+// hasNonGLSLVariant reports whether prog has a recorded variant for stage
+// in some backend other than GLSL. validateProgram uses this to tell a
+// genuinely missing shader apart from one that was simply never built as
+// GLSL text, e.g. because -backends excluded "glsl" or the program only
+// ships a precompiled backend for that stage.
+func hasNonGLSLVariant(prog, stage string) bool {
+	for _, v := range templData.ProgramVariants[prog] {
+		if v.Stage == stage && v.Backend != backendExt[SHADEREXT] {
+			return true
+		}
+	}
+	return false
+}
+
+// glslangAvailable reports whether glslangValidator is on PATH.
+func glslangAvailable() bool {
+	_, err := exec.LookPath("glslangValidator")
+	return err == nil
+}
+
+// runGlslangValidator compiles source as stage through glslangValidator,
+// returning one diagnostic per ERROR line it reports.
+func runGlslangValidator(name, stage, source string) []diagnostic {
+
+	ext := glslangStageExt[stage]
+	tmp, err := ioutil.TempFile("", "g3nshaders-*"+ext)
+	if err != nil {
+		return []diagnostic{{File: name, Msg: fmt.Sprintf("validate: %v", err)}}
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(source); err != nil {
+		tmp.Close()
+		return []diagnostic{{File: name, Msg: fmt.Sprintf("validate: %v", err)}}
+	}
+	tmp.Close()
+
+	var out bytes.Buffer
+	cmd := exec.Command("glslangValidator", tmp.Name())
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	cmd.Run() // non-zero exit just means errors were found, below
+
+	var diags []diagnostic
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		m := reGlslangDiag.FindStringSubmatch(scanner.Text())
+		if m == nil || m[1] != "ERROR" {
+			continue
+		}
+		line := 0
+		fmt.Sscanf(m[2], "%d", &line)
+		diags = append(diags, diagnostic{File: name, Line: line, Msg: m[3]})
+	}
+	return diags
+}
+
+// checkBalanced is the built-in fallback used when glslangValidator is
+// unavailable: it flags unbalanced braces/parens, the most common GLSL
+// syntax error and the only one that can be checked without a real
+// parser.
+func checkBalanced(name, source string) []diagnostic {
+
+	stripped := stripComments(source)
+	var diags []diagnostic
+	var braces, parens int
+	for i, r := range stripped {
+		switch r {
+		case '{':
+			braces++
+		case '}':
+			braces--
+		case '(':
+			parens++
+		case ')':
+			parens--
+		}
+		if braces < 0 || parens < 0 {
+			line := 1 + strings.Count(stripped[:i], "\n")
+			diags = append(diags, diagnostic{File: name, Line: line, Msg: "unbalanced braces or parentheses"})
+			return diags
+		}
+	}
+	if braces != 0 || parens != 0 {
+		diags = append(diags, diagnostic{File: name, Msg: "unbalanced braces or parentheses"})
+	}
+	return diags
+}
+
+// stripComments removes GLSL '//' line comments and '/* */' block
+// comments from source, replacing each with an equivalent run of
+// newlines so line numbers in source stay aligned with the result.
+func stripComments(source string) string {
+
+	var out strings.Builder
+	runes := []rune(source)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] == '/' && i+1 < len(runes) && runes[i+1] == '/' {
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			if i < len(runes) {
+				out.WriteRune('\n')
+			}
+			continue
+		}
+		if runes[i] == '/' && i+1 < len(runes) && runes[i+1] == '*' {
+			i += 2
+			for i+1 < len(runes) && !(runes[i] == '*' && runes[i+1] == '/') {
+				if runes[i] == '\n' {
+					out.WriteRune('\n')
+				}
+				i++
+			}
+			i++
+			continue
+		}
+		out.WriteRune(runes[i])
+	}
+	return out.String()
+}
+
+// reVarying matches a GLSL ES 1.00 'varying' declaration or a GLSL 1.30+
+// 'in'/'out' declaration, capturing its type and name.
+var reVarying = regexp.MustCompile(`^\s*(?:varying|in|out)\s+(?:flat\s+|smooth\s+|noperspective\s+)?(\w+)\s+(\w+)\s*;`)
+
+// reLayout matches a leading GLSL 'layout(...)' qualifier, e.g.
+// "layout(location = 0) ", routine on 'in'/'out' declarations in the GLSL
+// 330+ desktop profile. shaderVaryings strips it before matching reVarying.
+var reLayout = regexp.MustCompile(`^layout\s*\([^)]*\)\s*`)
+
+// shaderVaryings returns the name -> type of every varying/in/out
+// declaration of the given direction ("out" for a shader's outputs, "in"
+// for a shader's inputs) in source.
+func shaderVaryings(source, direction string) map[string]string {
+
+	vars := make(map[string]string)
+	for _, line := range strings.Split(source, "\n") {
+		trimmed := strings.TrimSpace(line)
+		trimmed = reLayout.ReplaceAllString(trimmed, "")
+		if direction == "out" && !strings.HasPrefix(trimmed, "out ") && !strings.HasPrefix(trimmed, "varying ") {
+			continue
+		}
+		if direction == "in" && !strings.HasPrefix(trimmed, "in ") && !strings.HasPrefix(trimmed, "varying ") {
+			continue
+		}
+		m := reVarying.FindStringSubmatch(trimmed)
+		if m == nil {
+			continue
+		}
+		vars[m[2]] = m[1]
+	}
+	return vars
+}
+
+// checkInterface reports every varying the fragment shader reads that
+// the vertex shader does not write, or writes with a different type.
+// Vertex outputs the fragment shader never reads are not flagged: a
+// shader is free to leave values unused downstream.
+func checkInterface(vertName, vertSource, fragName, fragSource string) []diagnostic {
+
+	outs := shaderVaryings(vertSource, "out")
+	ins := shaderVaryings(fragSource, "in")
+
+	var diags []diagnostic
+	for name, typ := range ins {
+		outType, ok := outs[name]
+		if !ok {
+			diags = append(diags, diagnostic{
+				File: fragName,
+				Msg:  fmt.Sprintf("input %q has no matching output in vertex shader %q", name, vertName),
+			})
+			continue
+		}
+		if outType != typ {
+			diags = append(diags, diagnostic{
+				File: fragName,
+				Msg:  fmt.Sprintf("input %q is %s but vertex shader %q declares it %s", name, typ, vertName, outType),
+			})
+		}
+	}
+	return diags
+}
@@ -0,0 +1,104 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import "testing"
+
+func TestCheckBalanced(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		source  string
+		wantErr bool
+	}{
+		{"balanced", "void main() {\n  vec3 v = vec3(1.0);\n}", false},
+		{"unbalanced brace", "void main() {\n  vec3 v = vec3(1.0);\n", true},
+		{"unbalanced paren", "void main() {\n  vec3 v = vec3(1.0;\n}", true},
+		{"brace in line comment", "void main() {\n  // if (x) { this does not count }\n}", false},
+		{"brace in block comment", "void main() {\n  /* { unbalanced inside a comment */\n}", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diags := checkBalanced(tt.name, tt.source)
+			if gotErr := len(diags) > 0; gotErr != tt.wantErr {
+				t.Errorf("checkBalanced(%q) = %v, wantErr %v", tt.source, diags, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateProgramSkipsBackendFilteredStages(t *testing.T) {
+
+	info := progInfo{Vertex: "standard_vertex", Fragment: "standard_fragment"}
+	shaders := map[string]fileInfo{} // no GLSL text built for either stage
+	templData.ProgramVariants = map[string][]variantInfo{
+		"standard": {
+			{Prog: "standard", Stage: TYPE_VERTEX, Backend: "spirv"},
+			{Prog: "standard", Stage: TYPE_FRAGMENT, Backend: "spirv"},
+		},
+	}
+	defer func() { templData.ProgramVariants = nil }()
+
+	diags := validateProgram("standard", info, shaders, false, make(map[string]bool))
+	if len(diags) != 0 {
+		t.Errorf("validateProgram() = %v, want no diagnostics for a program with no GLSL variant", diags)
+	}
+}
+
+func TestValidateProgramReportsGenuinelyMissingShader(t *testing.T) {
+
+	info := progInfo{Vertex: "standard_vertex", Fragment: "standard_fragment"}
+	shaders := map[string]fileInfo{} // neither stage built, and no other backend either
+	templData.ProgramVariants = map[string][]variantInfo{}
+	defer func() { templData.ProgramVariants = nil }()
+
+	diags := validateProgram("standard", info, shaders, false, make(map[string]bool))
+	if len(diags) != 2 {
+		t.Errorf("validateProgram() = %v, want 2 \"shader not found\" diagnostics", diags)
+	}
+}
+
+func TestCheckInterface(t *testing.T) {
+
+	tests := []struct {
+		name       string
+		vertSource string
+		fragSource string
+		wantDiags  int
+	}{
+		{
+			name:       "matching plain varying",
+			vertSource: "out vec3 Color;\nvoid main() {}",
+			fragSource: "in vec3 Color;\nvoid main() {}",
+			wantDiags:  0,
+		},
+		{
+			name:       "matching layout-qualified varying",
+			vertSource: "layout(location = 0) out vec3 Color;\nvoid main() {}",
+			fragSource: "in vec3 Color;\nvoid main() {}",
+			wantDiags:  0,
+		},
+		{
+			name:       "missing output",
+			vertSource: "void main() {}",
+			fragSource: "in vec3 Color;\nvoid main() {}",
+			wantDiags:  1,
+		},
+		{
+			name:       "type mismatch",
+			vertSource: "out vec4 Color;\nvoid main() {}",
+			fragSource: "in vec3 Color;\nvoid main() {}",
+			wantDiags:  1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diags := checkInterface("vert", tt.vertSource, "frag", tt.fragSource)
+			if len(diags) != tt.wantDiags {
+				t.Errorf("checkInterface() = %v, want %d diagnostic(s)", diags, tt.wantDiags)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package shaderutil
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// shaderExt is the file extension recognized as a shader or include source.
+const shaderExt = ".glsl"
+
+// includeDir is the name of the subdirectory (at any depth) whose files
+// are treated as includes instead of shader programs.
+const includeDir = "include"
+
+// DirLoader reads a directory tree of ".glsl" shader and include sources,
+// the same layout g3nshaders itself scans, and can watch that tree for
+// changes so a running program can reload shaders without a rebuild.
+type DirLoader struct {
+	dir string
+}
+
+// NewDirLoader creates a DirLoader rooted at dir.
+func NewDirLoader(dir string) *DirLoader {
+
+	return &DirLoader{dir: dir}
+}
+
+// Load reads every shader and include source under the loader's directory,
+// resolves '#include' directives and expands '#pragma g3n permute'
+// directives against them exactly as g3nshaders does at generate time, and
+// returns the results keyed by name, in the same form as the maps embedded
+// by g3nshaders into the generated sources.go.
+func (dl *DirLoader) Load() (includes map[string]string, shaders map[string]string, err error) {
+
+	includes = make(map[string]string)
+	raw := make(map[string]string)
+	if err := dl.walk(dl.dir, false, includes, raw); err != nil {
+		return nil, nil, err
+	}
+	shaders, err = ExpandShaders(raw, includes)
+	if err != nil {
+		return nil, nil, err
+	}
+	return includes, shaders, nil
+}
+
+// walk recursively collects shader and include sources under dir into the
+// supplied maps, mirroring g3nshaders' own processDir/processFile logic.
+func (dl *DirLoader) walk(dir string, include bool, includes, shaders map[string]string) error {
+
+	finfos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, fi := range finfos {
+		path := filepath.Join(dir, fi.Name())
+		if fi.IsDir() {
+			dirInclude := include
+			if fi.Name() == includeDir {
+				dirInclude = true
+			}
+			if err := dl.walk(path, dirInclude, includes, shaders); err != nil {
+				return err
+			}
+			continue
+		}
+		if filepath.Ext(fi.Name()) != shaderExt {
+			continue
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		fbase := fi.Name()
+		fname := fbase[:len(fbase)-len(shaderExt)]
+		if include {
+			includes[fname] = string(data)
+		} else {
+			shaders[fname] = string(data)
+		}
+	}
+	return nil
+}
+
+// Watch watches the loader's directory tree for writes to ".glsl" files
+// and sends a ReloadEvent on the returned channel for every affected
+// shader program name whenever one or more of its sources change. The
+// channel is closed when ctx is cancelled.
+//
+// programOf maps a changed shader or include name to the program names
+// that must be relinked as a result; it is supplied by the caller because
+// only the generated package knows which programs reference which
+// includes and shaders.
+func (dl *DirLoader) Watch(ctx context.Context, programOf func(name string) []string) (<-chan ReloadEvent, error) {
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := dl.addRecursive(watcher, dl.dir); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	events := make(chan ReloadEvent)
+	go func() {
+		defer watcher.Close()
+		defer close(events)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ev, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if ev.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+					continue
+				}
+				if filepath.Ext(ev.Name) != shaderExt {
+					continue
+				}
+				fbase := filepath.Base(ev.Name)
+				fname := fbase[:len(fbase)-len(shaderExt)]
+				for _, prog := range programOf(fname) {
+					events <- ReloadEvent{Program: prog}
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				events <- ReloadEvent{Err: fmt.Errorf("shaderutil: watch error: %w", err)}
+			}
+		}
+	}()
+	return events, nil
+}
+
+// addRecursive adds dir and all its subdirectories to watcher, since
+// fsnotify does not watch directory trees recursively on its own.
+func (dl *DirLoader) addRecursive(watcher *fsnotify.Watcher, dir string) error {
+
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return watcher.Add(path)
+		}
+		return nil
+	})
+}
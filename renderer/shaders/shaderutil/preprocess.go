@@ -0,0 +1,211 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package shaderutil
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PermuteDirective is one '#pragma g3n permute KEY=A,B,C' line found in a
+// shader source, naming the values that key may take across permutations.
+type PermuteDirective struct {
+	Key    string
+	Values []string
+}
+
+// ResolveIncludes expands every '#include "name"' line in src, recursing
+// into the named include's own source, and detecting cycles via stack (the
+// chain of include names currently being expanded). file identifies src in
+// error messages.
+//
+// withLineDirectives controls whether a resolved include is wrapped in
+// '#line' directives pointing back to its own file: useful so a debug
+// build can map a compiler error back to the original .glsl source, but
+// GLSL's '#line' takes a decimal source-string-number as its second
+// parameter, not a filename, so this form must not reach sources that are
+// actually compiled by a GL driver.
+func ResolveIncludes(file, src string, includes map[string]string, stack []string, withLineDirectives bool) (string, error) {
+
+	lines := strings.Split(src, "\n")
+	var out strings.Builder
+	for i, line := range lines {
+		name, ok := IncludeName(line)
+		if !ok {
+			out.WriteString(line)
+			if i < len(lines)-1 {
+				out.WriteByte('\n')
+			}
+			continue
+		}
+		for _, s := range stack {
+			if s == name {
+				return "", fmt.Errorf("%s:%d: include cycle detected: %s -> %s", file, i+1, strings.Join(append(stack, name), " -> "), name)
+			}
+		}
+		incSrc, ok := includes[name]
+		if !ok {
+			return "", fmt.Errorf("%s:%d: #include %q: no such include", file, i+1, name)
+		}
+		incFile := includeFileRef(name)
+		incResolved, err := ResolveIncludes(incFile, incSrc, includes, append(stack, name), withLineDirectives)
+		if err != nil {
+			return "", err
+		}
+		if withLineDirectives {
+			fmt.Fprintf(&out, "#line 1 %q\n%s\n#line %d %q\n", incFile, incResolved, i+2, file)
+			continue
+		}
+		out.WriteString(incResolved)
+		if i < len(lines)-1 {
+			out.WriteByte('\n')
+		}
+	}
+	return out.String(), nil
+}
+
+// includeFileRef formats an include name as it should appear in #line
+// directives and error messages.
+func includeFileRef(name string) string {
+	return includeDir + "/" + name + shaderExt
+}
+
+// IncludeName reports the include name named by an '#include "name"'
+// line, ignoring leading whitespace, or ok=false if line is not one.
+func IncludeName(line string) (name string, ok bool) {
+	trimmed := strings.TrimSpace(line)
+	if !strings.HasPrefix(trimmed, "#include") {
+		return "", false
+	}
+	rest := strings.TrimSpace(trimmed[len("#include"):])
+	if len(rest) < 2 || rest[0] != '"' {
+		return "", false
+	}
+	end := strings.IndexByte(rest[1:], '"')
+	if end < 0 {
+		return "", false
+	}
+	return rest[1 : 1+end], true
+}
+
+// ExtractPermutations pulls every '#pragma g3n permute KEY=A,B,C' line out
+// of src, returning them in declaration order along with src with those
+// lines removed.
+func ExtractPermutations(src string) ([]PermuteDirective, string) {
+
+	lines := strings.Split(src, "\n")
+	var perms []PermuteDirective
+	var out []string
+	for _, line := range lines {
+		key, values, ok := PermuteDirectiveOf(line)
+		if !ok {
+			out = append(out, line)
+			continue
+		}
+		perms = append(perms, PermuteDirective{Key: key, Values: values})
+	}
+	return perms, strings.Join(out, "\n")
+}
+
+// PermuteDirectiveOf parses a '#pragma g3n permute KEY=A,B,C' line.
+func PermuteDirectiveOf(line string) (key string, values []string, ok bool) {
+	trimmed := strings.TrimSpace(line)
+	const prefix = "#pragma g3n permute "
+	if !strings.HasPrefix(trimmed, prefix) {
+		return "", nil, false
+	}
+	expr := strings.TrimSpace(trimmed[len(prefix):])
+	parts := strings.SplitN(expr, "=", 2)
+	if len(parts) != 2 {
+		return "", nil, false
+	}
+	for _, v := range strings.Split(parts[1], ",") {
+		values = append(values, strings.TrimSpace(v))
+	}
+	return strings.TrimSpace(parts[0]), values, true
+}
+
+// PermuteCombinations returns the cartesian product of perms' values, in
+// a deterministic order: perms are expanded in declaration order and, for
+// a given perm, its values are expanded in declaration order.
+func PermuteCombinations(perms []PermuteDirective) []map[string]string {
+
+	combos := []map[string]string{{}}
+	for _, p := range perms {
+		var next []map[string]string
+		for _, combo := range combos {
+			for _, v := range p.Values {
+				c := make(map[string]string, len(combo)+1)
+				for k, vv := range combo {
+					c[k] = vv
+				}
+				c[p.Key] = v
+				next = append(next, c)
+			}
+		}
+		combos = next
+	}
+	return combos
+}
+
+// ComboSuffix formats combo as the "__KEY=VALUE" suffix appended to a
+// shader's base name to name one of its concrete permutations, with keys
+// sorted for a deterministic name.
+func ComboSuffix(combo map[string]string) string {
+
+	keys := make([]string, 0, len(combo))
+	for k := range combo {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, "__%s=%s", k, combo[k])
+	}
+	return b.String()
+}
+
+// DefineLines renders combo as '#define KEY VALUE' lines, sorted for a
+// deterministic, reproducible output, to prepend to a permutation's
+// expanded source.
+func DefineLines(combo map[string]string) string {
+
+	keys := make([]string, 0, len(combo))
+	for k := range combo {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, "#define %s %s\n", k, combo[k])
+	}
+	return b.String()
+}
+
+// ExpandShaders resolves #include directives and expands #pragma g3n
+// permute directives for every shader in shaders against includes,
+// producing the same name -> source map g3nshaders bakes into shaderMap
+// at generate time: one entry per base shader (includes resolved, permute
+// pragmas stripped) plus one further entry per permutation, named
+// "<shader>__KEY=VALUE...". It does not emit '#line' directives, since the
+// result is handed straight to the GL driver rather than read by a
+// developer.
+func ExpandShaders(shaders, includes map[string]string) (map[string]string, error) {
+
+	out := make(map[string]string, len(shaders))
+	for name, src := range shaders {
+		resolved, err := ResolveIncludes(name, src, includes, nil, false)
+		if err != nil {
+			return nil, err
+		}
+		perms, stripped := ExtractPermutations(resolved)
+		out[name] = stripped
+		for _, combo := range PermuteCombinations(perms) {
+			out[name+ComboSuffix(combo)] = DefineLines(combo) + stripped
+		}
+	}
+	return out, nil
+}
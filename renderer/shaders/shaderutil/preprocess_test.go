@@ -0,0 +1,115 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package shaderutil
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResolveIncludesExpandsNestedIncludes(t *testing.T) {
+
+	includes := map[string]string{
+		"a": "A-before\n#include \"b\"\nA-after",
+		"b": "B-body",
+	}
+	got, err := ResolveIncludes("main.glsl", "#include \"a\"", includes, nil, false)
+	if err != nil {
+		t.Fatalf("ResolveIncludes: unexpected error: %v", err)
+	}
+	for _, want := range []string{"A-before", "B-body", "A-after"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("ResolveIncludes: result %q missing %q", got, want)
+		}
+	}
+	if strings.Contains(got, "#line") {
+		t.Errorf("ResolveIncludes: result %q contains a #line directive with withLineDirectives=false", got)
+	}
+}
+
+func TestResolveIncludesWithLineDirectives(t *testing.T) {
+
+	includes := map[string]string{"a": "A-body"}
+	got, err := ResolveIncludes("main.glsl", "#include \"a\"", includes, nil, true)
+	if err != nil {
+		t.Fatalf("ResolveIncludes: unexpected error: %v", err)
+	}
+	if !strings.Contains(got, "#line") {
+		t.Errorf("ResolveIncludes: result %q missing a #line directive with withLineDirectives=true", got)
+	}
+}
+
+func TestResolveIncludesDetectsCycle(t *testing.T) {
+
+	includes := map[string]string{
+		"a": "#include \"b\"",
+		"b": "#include \"a\"",
+	}
+	_, err := ResolveIncludes("main.glsl", "#include \"a\"", includes, nil, false)
+	if err == nil {
+		t.Fatal("ResolveIncludes: expected an include cycle error, got nil")
+	}
+}
+
+func TestResolveIncludesMissingInclude(t *testing.T) {
+
+	_, err := ResolveIncludes("main.glsl", "#include \"missing\"", nil, nil, false)
+	if err == nil {
+		t.Fatal("ResolveIncludes: expected a missing-include error, got nil")
+	}
+}
+
+func TestPermuteCombinations(t *testing.T) {
+
+	perms := []PermuteDirective{
+		{Key: "SKIN", Values: []string{"0", "1"}},
+		{Key: "FOG", Values: []string{"0", "1"}},
+	}
+	combos := PermuteCombinations(perms)
+	if len(combos) != 4 {
+		t.Fatalf("PermuteCombinations: got %d combinations, want 4", len(combos))
+	}
+
+	seen := make(map[string]bool)
+	for _, c := range combos {
+		seen[ComboSuffix(c)] = true
+	}
+	for _, want := range []string{
+		"__FOG=0__SKIN=0",
+		"__FOG=0__SKIN=1",
+		"__FOG=1__SKIN=0",
+		"__FOG=1__SKIN=1",
+	} {
+		if !seen[want] {
+			t.Errorf("PermuteCombinations: missing combination %q", want)
+		}
+	}
+}
+
+func TestExpandShaders(t *testing.T) {
+
+	shaders := map[string]string{
+		"standard_vertex": "#include \"common\"\n#pragma g3n permute SKIN=0,1\nvoid main() {}",
+	}
+	includes := map[string]string{"common": "// common code"}
+
+	expanded, err := ExpandShaders(shaders, includes)
+	if err != nil {
+		t.Fatalf("ExpandShaders: unexpected error: %v", err)
+	}
+	for _, name := range []string{"standard_vertex", "standard_vertex__SKIN=0", "standard_vertex__SKIN=1"} {
+		src, ok := expanded[name]
+		if !ok {
+			t.Errorf("ExpandShaders: missing entry %q", name)
+			continue
+		}
+		if strings.Contains(src, "#pragma g3n permute") {
+			t.Errorf("ExpandShaders: %q still contains a permute pragma", name)
+		}
+	}
+	if !strings.Contains(expanded["standard_vertex__SKIN=1"], "#define SKIN 1") {
+		t.Errorf("ExpandShaders: %q missing its #define", "standard_vertex__SKIN=1")
+	}
+}
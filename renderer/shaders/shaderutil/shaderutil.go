@@ -0,0 +1,16 @@
+// Copyright 2016 The G3N Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package shaderutil provides the runtime support code shared by the
+// shader packages generated by g3nshaders. Generated packages embed the
+// shader sources at build time but use this package to re-read and
+// re-resolve those sources from disk during development.
+package shaderutil
+
+// ReloadEvent describes the result of (re)loading a shader program from
+// disk while a Loader is watching a shader source directory for changes.
+type ReloadEvent struct {
+	Program string // name of the program that was (re)loaded
+	Err     error  // non-nil if loading or relinking this program failed
+}